@@ -0,0 +1,68 @@
+package zk
+
+import (
+	"errors"
+
+	"github.com/samuel/go-zookeeper/zk"
+)
+
+// ErrKeyModified is returned by AtomicSet and AtomicDelete when the znode's
+// version no longer matches the stat passed in, i.e. another writer changed
+// it since it was last read.
+var ErrKeyModified = errors.New("zk: key was modified since last read")
+
+// errNoPreviousStat is returned by AtomicSet and AtomicDelete when called
+// with a nil previous Stat, which otherwise has no sensible version to
+// compare against.
+var errNoPreviousStat = errors.New("zk: previous Stat is required, see GetWithStat")
+
+// GetWithStat returns the value and Stat associated with path, or error if
+// the path does not exist. The returned Stat can be passed to AtomicSet or
+// AtomicDelete to implement an optimistic-concurrency read-modify-write.
+func (zook *ZooKeeper) GetWithStat(path string) ([]byte, *zk.Stat, error) {
+	connection, err := zook.connect()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	data, stat, err := connection.Get(path)
+	return zook.decodeValue(data), stat, err
+}
+
+// AtomicSet updates path to data only if it is still at the version found in
+// previous, translating a version mismatch into ErrKeyModified.
+func (zook *ZooKeeper) AtomicSet(path string, data []byte, previous *zk.Stat) (*zk.Stat, error) {
+	if previous == nil {
+		return nil, errNoPreviousStat
+	}
+
+	connection, err := zook.connect()
+	if err != nil {
+		return nil, err
+	}
+
+	stat, err := connection.Set(path, zook.encodeValue(data), previous.Version)
+	if err == zk.ErrBadVersion {
+		return nil, ErrKeyModified
+	}
+	return stat, err
+}
+
+// AtomicDelete removes path only if it is still at the version found in
+// previous, translating a version mismatch into ErrKeyModified.
+func (zook *ZooKeeper) AtomicDelete(path string, previous *zk.Stat) error {
+	if previous == nil {
+		return errNoPreviousStat
+	}
+
+	connection, err := zook.connect()
+	if err != nil {
+		return err
+	}
+
+	err = connection.Delete(path, previous.Version)
+	if err == zk.ErrBadVersion {
+		return ErrKeyModified
+	}
+	return err
+}
@@ -0,0 +1,214 @@
+// Package kafka reads the well-known Kafka ZooKeeper metadata layout -
+// brokers, topics, partition state, consumer offsets, and the controller -
+// over a shared zk.ZooKeeper connection, the way kazoo-go exposes it to
+// Kafka exporters.
+package kafka
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/jbvmio/zk"
+)
+
+const (
+	brokersPath    = "/brokers/ids"
+	topicsPath     = "/brokers/topics"
+	consumersPath  = "/consumers"
+	controllerPath = "/controller"
+)
+
+// Metadata reads Kafka's ZooKeeper metadata layout over zook.
+type Metadata struct {
+	zook *zk.ZooKeeper
+}
+
+// New returns a Metadata reader backed by zook.
+func New(zook *zk.ZooKeeper) *Metadata {
+	return &Metadata{zook: zook}
+}
+
+// Broker is the JSON document Kafka stores at /brokers/ids/<id>.
+type Broker struct {
+	ID      int32  `json:"-"`
+	Host    string `json:"host"`
+	Port    int    `json:"port"`
+	JMXPort int    `json:"jmx_port"`
+	Version int    `json:"version"`
+}
+
+// Addr returns the broker's host:port.
+func (b Broker) Addr() string {
+	return fmt.Sprintf("%s:%d", b.Host, b.Port)
+}
+
+// Brokers returns every broker registered under /brokers/ids.
+func (m *Metadata) Brokers() ([]Broker, error) {
+	ids, err := m.zook.Children(brokersPath)
+	if err != nil {
+		return nil, err
+	}
+
+	brokers := make([]Broker, 0, len(ids))
+	for _, id := range ids {
+		data, err := m.zook.Get(brokersPath + "/" + id)
+		if err != nil {
+			return nil, err
+		}
+		var broker Broker
+		if err := json.Unmarshal(data, &broker); err != nil {
+			return nil, err
+		}
+		n, err := strconv.ParseInt(id, 10, 32)
+		if err != nil {
+			return nil, err
+		}
+		broker.ID = int32(n)
+		brokers = append(brokers, broker)
+	}
+	return brokers, nil
+}
+
+// topicMeta is the JSON document Kafka stores at /brokers/topics/<name>.
+type topicMeta struct {
+	Version    int                `json:"version"`
+	Partitions map[string][]int32 `json:"partitions"`
+}
+
+// PartitionState is the JSON document Kafka stores at
+// /brokers/topics/<name>/partitions/<id>/state.
+type PartitionState struct {
+	ControllerEpoch int     `json:"controller_epoch"`
+	Leader          int32   `json:"leader"`
+	Version         int     `json:"version"`
+	ISR             []int32 `json:"isr"`
+}
+
+// Topic describes a Kafka topic's partition assignment and, where
+// available, each partition's live state.
+type Topic struct {
+	Name       string
+	Partitions map[int32][]int32 // partition -> replica broker ids, in order
+	State      map[int32]PartitionState
+}
+
+// Topics returns every topic registered under /brokers/topics.
+func (m *Metadata) Topics() ([]Topic, error) {
+	names, err := m.zook.Children(topicsPath)
+	if err != nil {
+		return nil, err
+	}
+
+	topics := make([]Topic, 0, len(names))
+	for _, name := range names {
+		topic, err := m.Topic(name)
+		if err != nil {
+			return nil, err
+		}
+		topics = append(topics, *topic)
+	}
+	return topics, nil
+}
+
+// Topic returns the partition assignment and state for a single topic.
+func (m *Metadata) Topic(name string) (*Topic, error) {
+	data, err := m.zook.Get(topicsPath + "/" + name)
+	if err != nil {
+		return nil, err
+	}
+
+	var meta topicMeta
+	if err := json.Unmarshal(data, &meta); err != nil {
+		return nil, err
+	}
+
+	topic := &Topic{
+		Name:       name,
+		Partitions: make(map[int32][]int32, len(meta.Partitions)),
+		State:      make(map[int32]PartitionState, len(meta.Partitions)),
+	}
+	for partStr, replicas := range meta.Partitions {
+		part, err := strconv.ParseInt(partStr, 10, 32)
+		if err != nil {
+			return nil, err
+		}
+		topic.Partitions[int32(part)] = replicas
+
+		statePath := fmt.Sprintf("%s/%s/partitions/%s/state", topicsPath, name, partStr)
+		stateData, err := m.zook.Get(statePath)
+		if err != nil {
+			// Partition state may not exist yet while the topic is being created.
+			continue
+		}
+		var state PartitionState
+		if err := json.Unmarshal(stateData, &state); err == nil {
+			topic.State[int32(part)] = state
+		}
+	}
+	return topic, nil
+}
+
+// ConsumerGroups returns the names of every registered consumer group.
+func (m *Metadata) ConsumerGroups() ([]string, error) {
+	return m.zook.Children(consumersPath)
+}
+
+// ConsumerGroupOffsets returns the committed offset of every partition the
+// group has committed to, keyed by topic then partition.
+func (m *Metadata) ConsumerGroupOffsets(group string) (map[string]map[int32]int64, error) {
+	offsetsPath := fmt.Sprintf("%s/%s/offsets", consumersPath, group)
+	topics, err := m.zook.Children(offsetsPath)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make(map[string]map[int32]int64, len(topics))
+	for _, topic := range topics {
+		topicPath := offsetsPath + "/" + topic
+		parts, err := m.zook.Children(topicPath)
+		if err != nil {
+			return nil, err
+		}
+
+		offsets := make(map[int32]int64, len(parts))
+		for _, part := range parts {
+			data, err := m.zook.Get(topicPath + "/" + part)
+			if err != nil {
+				return nil, err
+			}
+			offset, err := strconv.ParseInt(strings.TrimSpace(string(data)), 10, 64)
+			if err != nil {
+				return nil, err
+			}
+			p, err := strconv.ParseInt(part, 10, 32)
+			if err != nil {
+				return nil, err
+			}
+			offsets[int32(p)] = offset
+		}
+		result[topic] = offsets
+	}
+	return result, nil
+}
+
+// controllerMeta is the JSON document Kafka stores at /controller.
+type controllerMeta struct {
+	Version  int   `json:"version"`
+	BrokerID int32 `json:"brokerid"`
+}
+
+// Controller returns the broker id of the current Kafka controller.
+func (m *Metadata) Controller() (int32, error) {
+	data, err := m.zook.Get(controllerPath)
+	if err != nil {
+		return 0, err
+	}
+
+	var meta controllerMeta
+	if err := json.Unmarshal(data, &meta); err != nil {
+		return 0, err
+	}
+	return meta.BrokerID, nil
+}
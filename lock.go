@@ -0,0 +1,334 @@
+package zk
+
+import (
+	"context"
+	"errors"
+	gopath "path"
+	"strconv"
+	"strings"
+
+	"github.com/samuel/go-zookeeper/zk"
+)
+
+const lockPrefix = "lock-"
+
+// parseSeq extracts the sequence number ZooKeeper appends to a sequential
+// znode's name, e.g. 11 from "lock-0000000011" or from the
+// "_c_<guid>-lock-0000000011" name CreateProtectedEphemeralSequential
+// produces. Candidate names carry a random per-client GUID prefix, so
+// ordering contenders by sequence number requires parsing this suffix out -
+// sorting the raw names would sort by that random prefix instead, the same
+// pitfall zk.Lock's own parseSeq avoids.
+func parseSeq(name string) (int64, error) {
+	parts := strings.Split(name, "-")
+	return strconv.ParseInt(parts[len(parts)-1], 10, 64)
+}
+
+// isLowestSeq reports whether name holds the lowest sequence number among
+// children, i.e. whether its creator is the one holding the lock.
+func isLowestSeq(name string, children []string) bool {
+	mySeq, err := parseSeq(name)
+	if err != nil {
+		return false
+	}
+	for _, child := range children {
+		seq, err := parseSeq(child)
+		if err != nil {
+			continue
+		}
+		if seq < mySeq {
+			return false
+		}
+	}
+	return true
+}
+
+// Lock is a distributed mutex built on the standard ZooKeeper locking
+// recipe: each contender creates a sequential ephemeral znode under path,
+// and the contender holding the lowest sequence number owns the lock.
+// Lock/Unlock wrap the vendor library's own zk.Lock, which already
+// implements and is tested against this recipe; TryLock is implemented
+// directly since zk.Lock has no non-blocking variant.
+// https://zookeeper.apache.org/doc/current/recipes.html#sc_recipes_Locks
+type Lock struct {
+	zook *ZooKeeper
+	path string
+	acl  []zk.ACL
+
+	lock    *zk.Lock // set once Lock acquires via the wrapped zk.Lock
+	tryPath string   // set once TryLock acquires directly
+
+	locked       chan struct{}
+	cancelListen func()
+}
+
+// NewLock returns a distributed lock rooted at path. acl governs the
+// candidate znodes created under path; path itself is created if it does
+// not already exist.
+func (zook *ZooKeeper) NewLock(path string, acl []zk.ACL) *Lock {
+	return &Lock{zook: zook, path: path, acl: acl}
+}
+
+// Locked returns a channel that is closed when the lock's session expires,
+// letting a caller that believes it still holds the lock abandon its work.
+func (l *Lock) Locked() <-chan struct{} {
+	return l.locked
+}
+
+// Lock blocks until the lock is acquired or ctx is done, delegating the
+// create/watch-predecessor dance to zk.Lock. zk.Lock.Lock has no
+// cancellation hook of its own, so a cancelled ctx makes Lock return
+// ctx.Err() right away while the acquire keeps running in the background;
+// if it later succeeds, the lock is released immediately since the caller
+// already gave up on it.
+func (l *Lock) Lock(ctx context.Context) error {
+	if l.lock != nil || l.tryPath != "" {
+		return errors.New("zk: already locked")
+	}
+
+	connection, err := l.zook.connect()
+	if err != nil {
+		return err
+	}
+	if _, err := l.zook.createInternal(connection, l.path, nil, l.acl, true); err != nil && err != zk.ErrNodeExists {
+		return err
+	}
+
+	lock := zk.NewLock(connection, l.path, l.acl)
+	done := make(chan error, 1)
+	go func() { done <- lock.Lock() }()
+
+	select {
+	case err := <-done:
+		if err != nil {
+			return err
+		}
+		l.lock = lock
+		l.arm()
+		return nil
+	case <-ctx.Done():
+		go func() {
+			if err := <-done; err == nil {
+				lock.Unlock()
+			}
+		}()
+		return ctx.Err()
+	}
+}
+
+// TryLock attempts to acquire the lock without waiting on the current
+// holder. It returns false, rather than blocking, if the lock is held.
+func (l *Lock) TryLock() (bool, error) {
+	if l.lock != nil || l.tryPath != "" {
+		return false, errors.New("zk: already locked")
+	}
+
+	connection, err := l.zook.connect()
+	if err != nil {
+		return false, err
+	}
+	if _, err := l.zook.createInternal(connection, l.path, nil, l.acl, true); err != nil && err != zk.ErrNodeExists {
+		return false, err
+	}
+
+	created, err := connection.CreateProtectedEphemeralSequential(gopath.Join(l.path, lockPrefix), nil, l.acl)
+	if err != nil {
+		return false, err
+	}
+
+	children, _, err := connection.Children(l.path)
+	if err != nil {
+		connection.Delete(created, -1)
+		return false, err
+	}
+	if !isLowestSeq(gopath.Base(created), children) {
+		connection.Delete(created, -1)
+		return false, nil
+	}
+
+	l.tryPath = created
+	l.arm()
+	return true, nil
+}
+
+// Unlock releases the lock, however it was acquired.
+func (l *Lock) Unlock() error {
+	switch {
+	case l.lock != nil:
+		lock := l.lock
+		l.lock = nil
+		l.disarm()
+		return lock.Unlock()
+	case l.tryPath != "":
+		connection, err := l.zook.connect()
+		if err != nil {
+			return err
+		}
+		path := l.tryPath
+		l.tryPath = ""
+		l.disarm()
+		return connection.Delete(path, -1)
+	default:
+		return errors.New("zk: not locked")
+	}
+}
+
+// arm sets up Locked() to close once the session is lost.
+func (l *Lock) arm() {
+	l.locked = make(chan struct{})
+	locked := l.locked
+	l.cancelListen = l.zook.onSessionLoss(func() { closeOnce(locked) })
+}
+
+func (l *Lock) disarm() {
+	if l.cancelListen != nil {
+		l.cancelListen()
+		l.cancelListen = nil
+	}
+	l.locked = nil
+}
+
+func closeOnce(c chan struct{}) {
+	select {
+	case <-c:
+	default:
+		close(c)
+	}
+}
+
+// LeaderEventType identifies a LeaderElection state transition.
+type LeaderEventType int
+
+const (
+	// Elected is emitted when this candidate becomes the leader.
+	Elected LeaderEventType = iota
+	// Deposed is emitted when this candidate stops being the leader,
+	// whether because it was overtaken or its session was lost.
+	Deposed
+)
+
+// LeaderEvent reports a LeaderElection state transition.
+type LeaderEvent struct {
+	Type LeaderEventType
+}
+
+// LeaderElection implements leader election using the same
+// sequential-ephemeral-znode recipe as Lock: whichever candidate holds the
+// lowest sequence number under path is the leader.
+type LeaderElection struct {
+	zook *ZooKeeper
+	path string
+	id   []byte
+
+	seq    string
+	events chan LeaderEvent
+}
+
+// LeaderElection returns a new election rooted at path. id is stored as the
+// data of this candidate's znode, e.g. to advertise an address peers can
+// reach the leader on.
+func (zook *ZooKeeper) LeaderElection(path string, id []byte) *LeaderElection {
+	return &LeaderElection{
+		zook:   zook,
+		path:   path,
+		id:     id,
+		events: make(chan LeaderEvent, 1),
+	}
+}
+
+// Events returns the channel Elected/Deposed transitions are emitted on.
+func (le *LeaderElection) Events() <-chan LeaderEvent {
+	return le.events
+}
+
+// Run joins the election and emits Elected/Deposed events on Events() as
+// leadership changes, until ctx is done or an error occurs.
+func (le *LeaderElection) Run(ctx context.Context) error {
+	connection, err := le.zook.connect()
+	if err != nil {
+		return err
+	}
+
+	if le.seq == "" {
+		if _, err := le.zook.createInternal(connection, le.path, nil, le.zook.acl, true); err != nil && err != zk.ErrNodeExists {
+			return err
+		}
+		created, err := connection.CreateProtectedEphemeralSequential(gopath.Join(le.path, "n-"), le.id, le.zook.acl)
+		if err != nil {
+			return err
+		}
+		le.seq = gopath.Base(created)
+	}
+
+	leading := false
+	defer func() {
+		// Leadership ends with Run, whether ctx is done or an error such as
+		// the session's connection going away stopped it.
+		if leading {
+			select {
+			case le.events <- LeaderEvent{Type: Deposed}:
+			default:
+			}
+		}
+	}()
+	for {
+		children, _, err := connection.Children(le.path)
+		if err != nil {
+			return err
+		}
+
+		mySeq, err := parseSeq(le.seq)
+		if err != nil {
+			return err
+		}
+
+		predecessor := ""
+		predecessorSeq := int64(-1)
+		for _, child := range children {
+			seq, err := parseSeq(child)
+			if err != nil || seq >= mySeq {
+				continue
+			}
+			if seq > predecessorSeq {
+				predecessorSeq = seq
+				predecessor = child
+			}
+		}
+		isLeader := predecessor == ""
+
+		switch {
+		case isLeader && !leading:
+			select {
+			case le.events <- LeaderEvent{Type: Elected}:
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+			leading = true
+		case !isLeader && leading:
+			leading = false
+			select {
+			case le.events <- LeaderEvent{Type: Deposed}:
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		}
+
+		watchPath := gopath.Join(le.path, le.seq)
+		if predecessor != "" {
+			watchPath = gopath.Join(le.path, predecessor)
+		}
+		exists, _, watch, err := connection.ExistsW(watchPath)
+		if err != nil {
+			return err
+		}
+		if !exists {
+			continue
+		}
+
+		select {
+		case <-watch:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
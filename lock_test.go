@@ -0,0 +1,52 @@
+package zk
+
+import "testing"
+
+func TestParseSeq(t *testing.T) {
+	cases := []struct {
+		name    string
+		in      string
+		want    int64
+		wantErr bool
+	}{
+		{"plain", "lock-0000000011", 11, false},
+		{"guid prefixed", "_c_abc123-lock-0000000042", 42, false},
+		{"no separator", "0000000005", 5, false},
+		{"not numeric", "lock-abc", 0, true},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			got, err := parseSeq(c.in)
+			if (err != nil) != c.wantErr {
+				t.Fatalf("parseSeq(%q) error = %v, wantErr %v", c.in, err, c.wantErr)
+			}
+			if err == nil && got != c.want {
+				t.Errorf("parseSeq(%q) = %d, want %d", c.in, got, c.want)
+			}
+		})
+	}
+}
+
+func TestIsLowestSeq(t *testing.T) {
+	children := []string{"lock-0000000003", "lock-0000000001", "lock-0000000002"}
+
+	cases := []struct {
+		name string
+		in   string
+		want bool
+	}{
+		{"lowest", "lock-0000000001", true},
+		{"not lowest", "lock-0000000002", false},
+		{"highest", "lock-0000000003", false},
+		{"unparsable treated as not held", "lock-abc", false},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			if got := isLowestSeq(c.in, children); got != c.want {
+				t.Errorf("isLowestSeq(%q, children) = %v, want %v", c.in, got, c.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,81 @@
+package zk
+
+import (
+	"crypto/tls"
+	"net"
+	"time"
+)
+
+// Config holds the connection options accepted by NewZooKeeperWithConfig.
+type Config struct {
+	// Servers is the list of servers to connect to, in the same form
+	// accepted by SetServers.
+	Servers []string
+
+	// DialTimeout bounds the TCP handshake for a new connection. Zero
+	// leaves the client library's own default in place.
+	DialTimeout time.Duration
+
+	// SessionTimeout is the ZooKeeper session timeout negotiated on
+	// connect. Zero falls back to the package's previous hardcoded
+	// one-second default.
+	SessionTimeout time.Duration
+
+	// TLSConfig, if non-nil, upgrades every new connection to TLS using
+	// this configuration.
+	TLSConfig *tls.Config
+}
+
+// NewZooKeeperWithConfig returns a ZooKeeper configured from cfg, for
+// callers that need dial/session timeouts or TLS. NewZooKeeper continues to
+// work unchanged for callers that don't.
+func NewZooKeeperWithConfig(cfg Config) *ZooKeeper {
+	zook := NewZooKeeper()
+	zook.servers = cfg.Servers
+	zook.dialTimeout = cfg.DialTimeout
+	zook.sessionTimeout = cfg.SessionTimeout
+	zook.tlsConfig = cfg.TLSConfig
+	return zook
+}
+
+// SetDialTimeout sets the TCP dial timeout used when establishing a new
+// connection to the ensemble. The zero value leaves the client library's
+// own default in place.
+func (zook *ZooKeeper) SetDialTimeout(d time.Duration) {
+	zook.dialTimeout = d
+}
+
+// SetSessionTimeout sets the ZooKeeper session timeout negotiated on
+// connect, replacing the package's previous hardcoded one-second default.
+func (zook *ZooKeeper) SetSessionTimeout(d time.Duration) {
+	zook.sessionTimeout = d
+}
+
+// SetTLSConfig enables TLS on new connections, using cfg for the handshake.
+func (zook *ZooKeeper) SetTLSConfig(cfg *tls.Config) {
+	zook.tlsConfig = cfg
+}
+
+// SetSASL configures a SASL mechanism to authenticate with immediately after
+// connect, added via the same AddAuth path as SetAuth.
+func (zook *ZooKeeper) SetSASL(mechanism, username, password string) {
+	zook.saslMechanism = mechanism
+	zook.saslUsername = username
+	zook.saslPassword = password
+}
+
+// netDial is passed to the underlying client via zk.WithDialer so that
+// DialTimeout and TLSConfig apply to every connection it opens, including
+// ones made by the reconnect watchdog.
+func (zook *ZooKeeper) netDial(network, address string, timeout time.Duration) (net.Conn, error) {
+	dialTimeout := timeout
+	if zook.dialTimeout > 0 {
+		dialTimeout = zook.dialTimeout
+	}
+
+	if zook.tlsConfig != nil {
+		dialer := &net.Dialer{Timeout: dialTimeout}
+		return tls.DialWithDialer(dialer, network, address, zook.tlsConfig)
+	}
+	return net.DialTimeout(network, address, dialTimeout)
+}
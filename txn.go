@@ -0,0 +1,55 @@
+package zk
+
+import "github.com/samuel/go-zookeeper/zk"
+
+// Txn batches create/set/delete/check operations into a single atomic
+// zk.Conn.Multi call: either every queued operation succeeds, or none of
+// them take effect.
+type Txn struct {
+	zook *ZooKeeper
+	ops  []interface{}
+}
+
+// NewTxn returns an empty transaction builder.
+func (zook *ZooKeeper) NewTxn() *Txn {
+	return &Txn{zook: zook}
+}
+
+// Create queues creation of path with data and acl, using the zook's
+// currently configured flags (see SetEphemeral/SetSequencial).
+func (t *Txn) Create(path string, data []byte, acl []zk.ACL) *Txn {
+	t.ops = append(t.ops, &zk.CreateRequest{Path: path, Data: t.zook.encodeValue(data), Acl: acl, Flags: t.zook.flags})
+	return t
+}
+
+// SetData queues a data update of path, checked against version (-1 skips
+// the version check).
+func (t *Txn) SetData(path string, data []byte, version int32) *Txn {
+	t.ops = append(t.ops, &zk.SetDataRequest{Path: path, Data: t.zook.encodeValue(data), Version: version})
+	return t
+}
+
+// Delete queues removal of path, checked against version (-1 skips the
+// version check).
+func (t *Txn) Delete(path string, version int32) *Txn {
+	t.ops = append(t.ops, &zk.DeleteRequest{Path: path, Version: version})
+	return t
+}
+
+// Check queues a version assertion on path without modifying it, letting a
+// Txn include a compare-and-swap over a key none of its other ops touch.
+func (t *Txn) Check(path string, version int32) *Txn {
+	t.ops = append(t.ops, &zk.CheckVersionRequest{Path: path, Version: version})
+	return t
+}
+
+// Commit executes the queued operations atomically, returning one result per
+// queued op in order plus a combined error. A non-nil error means none of
+// the operations took effect.
+func (t *Txn) Commit() ([]zk.MultiResponse, error) {
+	connection, err := t.zook.connect()
+	if err != nil {
+		return nil, err
+	}
+	return connection.Multi(t.ops...)
+}
@@ -0,0 +1,265 @@
+package zk
+
+import (
+	gopath "path"
+	"sync"
+
+	"github.com/samuel/go-zookeeper/zk"
+)
+
+// Event is emitted by the Watch* family of methods.
+type Event struct {
+	Path string
+	Type zk.EventType
+	Data []byte
+
+	// Err is set when retrieving Data for this event failed (e.g. the node
+	// was deleted between the watch firing and the follow-up Get), or when
+	// the watch could not be re-armed at all. Type still reflects what the
+	// server reported, or the zero value if the error came from re-arming.
+	Err error
+}
+
+// CancelFunc stops a watch started by WatchData, WatchChildren, or WatchTree.
+type CancelFunc func()
+
+// WatchData watches path for data and existence changes, emitting an Event
+// each time it fires. The watch is automatically re-armed after each fire,
+// and again after the shared session reconnects, until cancel is called.
+func (zook *ZooKeeper) WatchData(path string) (<-chan Event, CancelFunc, error) {
+	events := make(chan Event, 1)
+
+	cancel, err := zook.watchLoop(
+		func(connection *zk.Conn) (<-chan zk.Event, error) {
+			_, _, watch, err := connection.GetW(path)
+			return watch, err
+		},
+		func(event zk.Event, armErr error) {
+			if armErr != nil {
+				events <- Event{Path: path, Type: event.Type, Err: armErr}
+				return
+			}
+			if event.Type == zk.EventNodeDeleted {
+				events <- Event{Path: path, Type: event.Type}
+				return
+			}
+			data, err := zook.Get(path)
+			events <- Event{Path: path, Type: event.Type, Data: data, Err: err}
+		},
+	)
+	if err != nil {
+		return nil, nil, err
+	}
+	return events, cancel, nil
+}
+
+// WatchChildren watches path for changes to its list of children, emitting
+// an Event each time it fires. The watch is automatically re-armed after
+// each fire, and again after the shared session reconnects, until cancel is
+// called.
+func (zook *ZooKeeper) WatchChildren(path string) (<-chan Event, CancelFunc, error) {
+	events := make(chan Event, 1)
+
+	cancel, err := zook.watchLoop(
+		func(connection *zk.Conn) (<-chan zk.Event, error) {
+			_, _, watch, err := connection.ChildrenW(path)
+			return watch, err
+		},
+		func(event zk.Event, armErr error) {
+			events <- Event{Path: path, Type: event.Type, Err: armErr}
+		},
+	)
+	if err != nil {
+		return nil, nil, err
+	}
+	return events, cancel, nil
+}
+
+// watchLoop arms a zk.Conn-level watch via arm, calls emit each time it
+// fires, and re-arms it: immediately (watches in the underlying client fire
+// at most once), and again whenever the shared session is replaced by a
+// reconnect. If a re-arm attempt fails (e.g. the node was deleted), emit is
+// called with the zero Event and the error instead of leaving the caller to
+// wait forever on a watch that will never fire again.
+func (zook *ZooKeeper) watchLoop(arm func(*zk.Conn) (<-chan zk.Event, error), emit func(zk.Event, error)) (CancelFunc, error) {
+	connection, err := zook.connect()
+	if err != nil {
+		return nil, err
+	}
+	watch, err := arm(connection)
+	if err != nil {
+		return nil, err
+	}
+
+	cancel := make(chan struct{})
+	rearm := make(chan struct{}, 1)
+	cur := watch
+
+	cancelRewatch := zook.onRewatch(func() {
+		select {
+		case rearm <- struct{}{}:
+		default:
+		}
+	})
+
+	go func() {
+		for {
+			select {
+			case event, ok := <-cur:
+				if !ok {
+					// The channel closing means the connection it was
+					// armed on is gone, which only happens as part of a
+					// reconnect that also pushes to rearm - but the two
+					// aren't synchronized, so this case can be picked
+					// before that push arrives. Treat it the same as a
+					// rearm signal rather than returning, or a close that
+					// wins the race would permanently kill the watch.
+					cur = nil
+					select {
+					case rearm <- struct{}{}:
+					default:
+					}
+					continue
+				}
+				emit(event, nil)
+				select {
+				case rearm <- struct{}{}:
+				default:
+				}
+			case <-rearm:
+				connection, err := zook.connect()
+				if err != nil {
+					emit(zk.Event{}, err)
+					continue
+				}
+				w, err := arm(connection)
+				if err != nil {
+					emit(zk.Event{}, err)
+					continue
+				}
+				cur = w
+			case <-cancel:
+				return
+			}
+		}
+	}()
+
+	return func() {
+		cancelRewatch()
+		close(cancel)
+	}, nil
+}
+
+// WatchTree recursively installs data and children watches over path and
+// every descendant, emitting a single stream of events as any node in the
+// subtree changes. Newly created children are watched automatically; the
+// watch for a removed child is torn down once its own events dry up.
+func (zook *ZooKeeper) WatchTree(path string) (<-chan Event, CancelFunc, error) {
+	events := make(chan Event, 16)
+	done := make(chan struct{})
+
+	var mu sync.Mutex
+	watched := map[string]CancelFunc{}
+
+	var watchNode func(p string)
+	watchNode = func(p string) {
+		mu.Lock()
+		if _, ok := watched[p]; ok {
+			mu.Unlock()
+			return
+		}
+		watched[p] = func() {}
+		mu.Unlock()
+
+		dataEvents, cancelData, err := zook.WatchData(p)
+		if err != nil {
+			mu.Lock()
+			delete(watched, p)
+			mu.Unlock()
+			return
+		}
+		childEvents, cancelChildren, err := zook.WatchChildren(p)
+		if err != nil {
+			cancelData()
+			mu.Lock()
+			delete(watched, p)
+			mu.Unlock()
+			return
+		}
+
+		mu.Lock()
+		watched[p] = func() {
+			cancelData()
+			cancelChildren()
+		}
+		mu.Unlock()
+
+		if children, err := zook.Children(p); err == nil {
+			for _, child := range children {
+				watchNode(gopath.Join(p, child))
+			}
+		}
+
+		go func() {
+			for {
+				select {
+				case e, ok := <-dataEvents:
+					if !ok {
+						return
+					}
+					select {
+					case events <- e:
+					case <-done:
+						return
+					}
+					if e.Type == zk.EventNodeDeleted && p != path {
+						mu.Lock()
+						select {
+						case <-done:
+						default:
+							watched[p]()
+							delete(watched, p)
+						}
+						mu.Unlock()
+						return
+					}
+				case e, ok := <-childEvents:
+					if !ok {
+						return
+					}
+					if children, err := zook.Children(p); err == nil {
+						for _, child := range children {
+							watchNode(gopath.Join(p, child))
+						}
+					}
+					select {
+					case events <- e:
+					case <-done:
+						return
+					}
+				case <-done:
+					return
+				}
+			}
+		}()
+	}
+
+	children, err := zook.Children(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	watchNode(path)
+	for _, child := range children {
+		watchNode(gopath.Join(path, child))
+	}
+
+	cancel := func() {
+		close(done)
+		mu.Lock()
+		for _, c := range watched {
+			c()
+		}
+		mu.Unlock()
+	}
+	return events, cancel, nil
+}
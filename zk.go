@@ -3,6 +3,7 @@ package zk
 
 import (
 	"bytes"
+	"crypto/tls"
 	"errors"
 	"fmt"
 	"math"
@@ -10,12 +11,22 @@ import (
 	"sort"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/outbrain/golib/log"
 	"github.com/samuel/go-zookeeper/zk"
 )
 
+// Reconnect backoff bounds for the session watchdog: starts at
+// reconnectInitialBackoff and doubles up to reconnectMaxBackoff, giving up
+// after reconnectMaxFailTimes consecutive failed dial attempts.
+const (
+	reconnectInitialBackoff = 3 * time.Second
+	reconnectMaxBackoff     = 45 * time.Second
+	reconnectMaxFailTimes   = 12
+)
+
 type ZooKeeper struct {
 	servers        []string
 	authScheme     string
@@ -25,6 +36,68 @@ type ZooKeeper struct {
 	// We assume complete access to all
 	flags int32
 	acl   []zk.ACL
+
+	mu     sync.Mutex
+	conn   *zk.Conn
+	events <-chan zk.Event
+	done   chan struct{}
+
+	// rewatch holds callbacks that re-arm watches lost on reconnect, keyed
+	// by an id so a cancelled watch can unregister itself.
+	rewatch       map[int]func()
+	nextRewatchID int
+
+	// lossListeners holds callbacks notified when the session expires,
+	// keyed by an id so callers can unregister once they no longer care
+	// (e.g. a Lock that has been unlocked).
+	lossListeners  map[int]func()
+	nextListenerID int
+
+	// emptySentinel enables substituting emptyValueSentinel for zero-length
+	// values on write, and stripping it back out on read. See
+	// SetEmptyValueSentinel.
+	emptySentinel bool
+
+	// dialTimeout, sessionTimeout and tlsConfig configure new connections;
+	// see SetDialTimeout, SetSessionTimeout and SetTLSConfig.
+	dialTimeout    time.Duration
+	sessionTimeout time.Duration
+	tlsConfig      *tls.Config
+
+	// saslMechanism, if set, is applied via AddAuth after connect; see
+	// SetSASL.
+	saslMechanism string
+	saslUsername  string
+	saslPassword  string
+}
+
+// emptyValueSentinel is written in place of a zero-length value when
+// SetEmptyValueSentinel is enabled, so a znode can still be told apart from
+// one that has no data at all once it gains children.
+const emptyValueSentinel = "\x01"
+
+// SetEmptyValueSentinel opts into writing a single SOH byte (\x01) in place
+// of a zero-length value on Set/Create, and transparently stripping it back
+// out on Get, so callers can round-trip empty values through znodes that may
+// later gain children.
+func (zook *ZooKeeper) SetEmptyValueSentinel(enable bool) {
+	zook.emptySentinel = enable
+}
+
+// encodeValue applies the empty-value sentinel substitution, if enabled.
+func (zook *ZooKeeper) encodeValue(data []byte) []byte {
+	if zook.emptySentinel && len(data) == 0 {
+		return []byte(emptyValueSentinel)
+	}
+	return data
+}
+
+// decodeValue reverses encodeValue, if enabled.
+func (zook *ZooKeeper) decodeValue(data []byte) []byte {
+	if zook.emptySentinel && len(data) == 1 && data[0] == emptyValueSentinel[0] {
+		return []byte{}
+	}
+	return data
 }
 
 func NewZooKeeper() *ZooKeeper {
@@ -89,21 +162,223 @@ func (_ infoLogger) Printf(format string, a ...interface{}) {
 	log.Infof(format, a...)
 }
 
-// connect
-func (zook *ZooKeeper) connect() (*zk.Conn, error) {
+// dial opens a brand new connection to zook.servers and re-applies auth.
+func (zook *ZooKeeper) dial() (*zk.Conn, <-chan zk.Event, error) {
+	sessionTimeout := zook.sessionTimeout
+	if sessionTimeout == 0 {
+		sessionTimeout = time.Second
+	}
+
 	var conn *zk.Conn
+	var events <-chan zk.Event
 	var errd error
-	if zook.logging {
-		conn, _, errd = zk.Connect(zook.servers, time.Second, zk.WithLogInfo(true))
+	if zook.dialTimeout > 0 || zook.tlsConfig != nil {
+		conn, events, errd = zk.Connect(zook.servers, sessionTimeout, zk.WithLogInfo(zook.logging), zk.WithDialer(zook.netDial))
 	} else {
-		conn, _, errd = zk.Connect(zook.servers, time.Second, zk.WithLogInfo(false))
+		conn, events, errd = zk.Connect(zook.servers, sessionTimeout, zk.WithLogInfo(zook.logging))
 	}
 	if errd == nil && zook.authScheme != "" {
 		log.Debugf("Add Auth %s %s", zook.authScheme, zook.authExpression)
 		errd = conn.AddAuth(zook.authScheme, zook.authExpression)
 	}
+	if errd == nil && zook.saslMechanism != "" {
+		log.Debugf("Add SASL Auth %s %s", zook.saslMechanism, zook.saslUsername)
+		errd = conn.AddAuth(zook.saslMechanism, []byte(zook.saslUsername+":"+zook.saslPassword))
+	}
+
+	return conn, events, errd
+}
+
+// Connect establishes the shared, long-lived session used by every method on
+// ZooKeeper. Calling it explicitly is optional: connect() lazily calls it on
+// first use. It is safe to call Connect more than once.
+func (zook *ZooKeeper) Connect() error {
+	_, err := zook.connect()
+	return err
+}
+
+// Close tears down the shared session, if one is open, and stops the
+// reconnect watchdog goroutine.
+func (zook *ZooKeeper) Close() {
+	zook.mu.Lock()
+	defer zook.mu.Unlock()
+	if zook.conn == nil {
+		return
+	}
+	close(zook.done)
+	zook.conn.Close()
+	zook.conn = nil
+}
+
+// connect returns the shared connection backing every method, dialing and
+// starting the reconnect watchdog on first use. Unlike a typical connect/close
+// pair, callers must not Close() the returned connection; use zook.Close() to
+// tear down the session.
+func (zook *ZooKeeper) connect() (*zk.Conn, error) {
+	zook.mu.Lock()
+	defer zook.mu.Unlock()
+
+	if zook.conn != nil {
+		return zook.conn, nil
+	}
+
+	conn, events, err := zook.dial()
+	if err != nil {
+		return nil, err
+	}
 
-	return conn, errd
+	zook.conn = conn
+	zook.events = events
+	zook.done = make(chan struct{})
+	go zook.watchSession()
+	return zook.conn, nil
+}
+
+// watchSession observes session-state transitions on the connection's event
+// channel. The underlying client already retries ordinary network blips
+// (StateDisconnected) on its own - resending auth and existing watches
+// against the same session - so watchSession only steps in once the server
+// reports the session itself is gone (StateExpired), at which point the old
+// ephemeral znodes and watches are unrecoverable and a brand-new session is
+// dialed with capped exponential backoff.
+func (zook *ZooKeeper) watchSession() {
+	backoff := reconnectInitialBackoff
+	failures := 0
+
+	zook.mu.Lock()
+	done := zook.done
+	events := zook.events
+	zook.mu.Unlock()
+
+	for {
+		select {
+		case <-done:
+			return
+		case event, ok := <-events:
+			if !ok {
+				return
+			}
+			switch event.State {
+			case zk.StateExpired:
+				if zook.logging {
+					log.Debugf("zk session %s, reconnecting", event.State)
+				}
+				zook.notifySessionLoss()
+				if !zook.reconnect(done, &backoff, &failures) {
+					return
+				}
+				zook.mu.Lock()
+				events = zook.events
+				zook.mu.Unlock()
+			case zk.StateConnected, zk.StateHasSession:
+				backoff = reconnectInitialBackoff
+				failures = 0
+			}
+		}
+	}
+}
+
+// nextBackoff doubles d, capped at reconnectMaxBackoff.
+func nextBackoff(d time.Duration) time.Duration {
+	d *= 2
+	if d > reconnectMaxBackoff {
+		d = reconnectMaxBackoff
+	}
+	return d
+}
+
+// reconnect redials with capped exponential backoff until it succeeds, the
+// watchdog is told to stop via done, or reconnectMaxFailTimes is reached. It
+// returns false if the watchdog should stop.
+func (zook *ZooKeeper) reconnect(done chan struct{}, backoff *time.Duration, failures *int) bool {
+	for {
+		select {
+		case <-done:
+			return false
+		case <-time.After(*backoff):
+		}
+
+		conn, events, err := zook.dial()
+		if err != nil {
+			*failures++
+			*backoff = nextBackoff(*backoff)
+			if *failures >= reconnectMaxFailTimes {
+				log.Errorf("zk: giving up reconnecting after %d attempts: %v", *failures, err)
+				return false
+			}
+			continue
+		}
+
+		zook.mu.Lock()
+		old := zook.conn
+		zook.conn = conn
+		zook.events = events
+		rearms := make([]func(), 0, len(zook.rewatch))
+		for _, rearm := range zook.rewatch {
+			rearms = append(rearms, rearm)
+		}
+		zook.mu.Unlock()
+		if old != nil {
+			old.Close()
+		}
+		for _, rearm := range rearms {
+			rearm()
+		}
+		return true
+	}
+}
+
+// onSessionLoss registers fn to be called whenever the shared session
+// expires, and returns a cancel func that unregisters it.
+func (zook *ZooKeeper) onSessionLoss(fn func()) (cancel func()) {
+	zook.mu.Lock()
+	if zook.lossListeners == nil {
+		zook.lossListeners = make(map[int]func())
+	}
+	id := zook.nextListenerID
+	zook.nextListenerID++
+	zook.lossListeners[id] = fn
+	zook.mu.Unlock()
+
+	return func() {
+		zook.mu.Lock()
+		delete(zook.lossListeners, id)
+		zook.mu.Unlock()
+	}
+}
+
+// onRewatch registers fn to be called after the shared session is replaced
+// following a reconnect, so a watch can re-arm itself against the new
+// connection. It returns a cancel func that unregisters fn.
+func (zook *ZooKeeper) onRewatch(fn func()) (cancel func()) {
+	zook.mu.Lock()
+	if zook.rewatch == nil {
+		zook.rewatch = make(map[int]func())
+	}
+	id := zook.nextRewatchID
+	zook.nextRewatchID++
+	zook.rewatch[id] = fn
+	zook.mu.Unlock()
+
+	return func() {
+		zook.mu.Lock()
+		delete(zook.rewatch, id)
+		zook.mu.Unlock()
+	}
+}
+
+// notifySessionLoss fires every registered loss listener.
+func (zook *ZooKeeper) notifySessionLoss() {
+	zook.mu.Lock()
+	listeners := make([]func(), 0, len(zook.lossListeners))
+	for _, fn := range zook.lossListeners {
+		listeners = append(listeners, fn)
+	}
+	zook.mu.Unlock()
+
+	for _, fn := range listeners {
+		fn()
+	}
 }
 
 // EnableLogger toggles the logger
@@ -120,7 +395,6 @@ func (zook *ZooKeeper) Exists(path string) (bool, error) {
 	if err != nil {
 		return false, err
 	}
-	defer connection.Close()
 
 	exists, _, err := connection.Exists(path)
 	return exists, err
@@ -132,10 +406,9 @@ func (zook *ZooKeeper) Get(path string) ([]byte, error) {
 	if err != nil {
 		return []byte{}, err
 	}
-	defer connection.Close()
 
 	data, _, err := connection.Get(path)
-	return data, err
+	return zook.decodeValue(data), err
 }
 
 func (zook *ZooKeeper) GetACL(path string) (data []string, err error) {
@@ -143,7 +416,6 @@ func (zook *ZooKeeper) GetACL(path string) (data []string, err error) {
 	if err != nil {
 		return nil, err
 	}
-	defer connection.Close()
 
 	perms, _, err := connection.GetACL(path)
 	return zook.aclsToString(perms), err
@@ -180,7 +452,6 @@ func (zook *ZooKeeper) HasChildren(path string) (bool, error) {
 	if err != nil {
 		return true, err
 	}
-	defer connection.Close()
 
 	children, _, err := connection.Children(path)
 	return (len(children) > 0), err
@@ -192,7 +463,6 @@ func (zook *ZooKeeper) Children(path string) ([]string, error) {
 	if err != nil {
 		return []string{}, err
 	}
-	defer connection.Close()
 
 	children, _, err := connection.Children(path)
 	return children, err
@@ -229,7 +499,6 @@ func (zook *ZooKeeper) ChildrenRecursive(path string) ([]string, error) {
 	if err != nil {
 		return []string{}, err
 	}
-	defer connection.Close()
 
 	result, err := zook.childrenRecursiveInternal(connection, path, "")
 	return result, err
@@ -292,7 +561,6 @@ func (zook *ZooKeeper) Create(path string, data []byte, aclstr string, force boo
 	if err != nil {
 		return "", err
 	}
-	defer connection.Close()
 
 	if len(aclstr) > 0 {
 		zook.acl, err = zook.parseACLString(aclstr)
@@ -301,7 +569,7 @@ func (zook *ZooKeeper) Create(path string, data []byte, aclstr string, force boo
 		}
 	}
 
-	return zook.createInternal(connection, path, data, zook.acl, force)
+	return zook.createInternal(connection, path, zook.encodeValue(data), zook.acl, force)
 }
 
 func (zook *ZooKeeper) CreateWithACL(path string, data []byte, force bool, perms []zk.ACL) (string, error) {
@@ -309,9 +577,8 @@ func (zook *ZooKeeper) CreateWithACL(path string, data []byte, force bool, perms
 	if err != nil {
 		return "", err
 	}
-	defer connection.Close()
 
-	return zook.createInternalWithACL(connection, path, data, force, perms)
+	return zook.createInternalWithACL(connection, path, zook.encodeValue(data), force, perms)
 }
 
 // Set updates a value for a given path, or returns with error if the path does not exist
@@ -320,9 +587,8 @@ func (zook *ZooKeeper) Set(path string, data []byte) (*zk.Stat, error) {
 	if err != nil {
 		return nil, err
 	}
-	defer connection.Close()
 
-	return connection.Set(path, data, -1)
+	return connection.Set(path, zook.encodeValue(data), -1)
 }
 
 // updates the ACL on a given path
@@ -331,7 +597,6 @@ func (zook *ZooKeeper) SetACL(path string, aclstr string, force bool) (string, e
 	if err != nil {
 		return "", err
 	}
-	defer connection.Close()
 
 	acl, err := zook.parseACLString(aclstr)
 	if err != nil {
@@ -415,7 +680,6 @@ func (zook *ZooKeeper) Delete(path string) error {
 	if err != nil {
 		return err
 	}
-	defer connection.Close()
 	return connection.Delete(path, -1)
 }
 
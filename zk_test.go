@@ -0,0 +1,27 @@
+package zk
+
+import (
+	"testing"
+	"time"
+)
+
+func TestNextBackoff(t *testing.T) {
+	cases := []struct {
+		name string
+		in   time.Duration
+		want time.Duration
+	}{
+		{"doubles below cap", 3 * time.Second, 6 * time.Second},
+		{"doubles up to cap", 30 * time.Second, 45 * time.Second},
+		{"stays capped", 45 * time.Second, 45 * time.Second},
+		{"caps overshoot", 40 * time.Second, 45 * time.Second},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			if got := nextBackoff(c.in); got != c.want {
+				t.Errorf("nextBackoff(%s) = %s, want %s", c.in, got, c.want)
+			}
+		})
+	}
+}